@@ -4,29 +4,38 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"math"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
+	"github.com/MichaelDarr/audmon/config"
 	"github.com/MichaelDarr/audmon/internal"
+	"github.com/MichaelDarr/audmon/monitor"
 	"github.com/gdamore/tcell/v2"
-	"github.com/gen2brain/malgo"
-	"github.com/rivo/tview"
-)
-
-const (
-	barColor                = tcell.ColorGreen
-	backgroundColorClipping = tcell.ColorRed
-	framesPerSecond         = 60
-	// volumeClipWarningDuration indicates how long the bar will red after a clip occurs.
-	volumeClipWarningDuration = time.Second * 3
 )
 
 var (
-	flagHorizontal bool
-	flagVersion    bool
+	flagHorizontal  bool
+	flagVersion     bool
+	flagDevice      string
+	flagListDevices bool
+	flagLoopback    bool
+	flagRangeDB     float64
+	flagChannels    uint
+	flagHistory     bool
+	flagSpectrogram bool
+	flagServeAddr   string
+
+	flagBarColor        string
+	flagClippingColor   string
+	flagClipHoldDecay   float64
+	flagClipHoldSeconds float64
+	flagFrameRate       uint
+	flagMeterType       string
+
+	flagKeyQuit        string
+	flagKeyFreeze      string
+	flagKeyOrientation string
+	flagKeyCycleDevice string
+	flagKeyResetPeak   string
 )
 
 func Execute() {
@@ -37,120 +46,95 @@ func Execute() {
 		os.Exit(0)
 	}
 
-	// Initialize malgo (go miniaudio wrapper) context
-	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, func(message string) {
-		log.Printf("malgo: %v", message)
-	})
+	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("failed to initialize malgo context: %v", err)
-	}
-	defer func() {
-		_ = ctx.Uninit()
-		ctx.Free()
-	}()
-
-	// Set up tui app
-	app := tview.NewApplication()
-	monitorBarDirection := tview.FlexRow
-	if flagHorizontal {
-		monitorBarDirection = tview.FlexColumn
-	}
-	monitorBar := tview.NewFlex().SetDirection(monitorBarDirection)
-	monitorBarFiller := tview.NewBox()
-	if !flagHorizontal {
-		monitorBar.AddItem(monitorBarFiller, 0, 1, false)
-	}
-	monitorBar.AddItem(tview.NewBox().SetBackgroundColor(barColor), 0, 1, false)
-	if flagHorizontal {
-		monitorBar.AddItem(monitorBarFiller, 0, 1, false)
-	}
-
-	// Configure audio capture device
-	captureDeviceConfig := malgo.DefaultDeviceConfig(malgo.Capture)
-	captureDeviceConfig.Capture.Format = malgo.FormatU8
-	captureDeviceConfig.Alsa.NoMMap = 1
-	captureDeviceConfig.PeriodSizeInMilliseconds = uint32((time.Second / framesPerSecond).Milliseconds())
-	var prevVolumeDisplayed float64 = 0
-	clippingTracker := recentClippingTracker{}
-	device, err := malgo.InitDevice(ctx.Context, captureDeviceConfig, malgo.DeviceCallbacks{
-		Data: func(_, pSample []byte, _ uint32) {
-			// Find the loudest sample within the period
-			var maxSample uint8 = 0
-			for _, sample := range pSample {
-				if sample > maxSample {
-					maxSample = sample
-				}
-			}
-
-			// When the volume clips, warn the user by changing the bar color.
-			if maxSample == 240 {
-				clippingTracker.IndicateClippingOccured()
-			}
-
-			// volume is a value between 0 (minimum) and 1 (maximum).
-			// The observed maximum (clipping) is 240, but the docs indicate that it should be 255. This
-			// is likely a configuration issue.
-			// The volume range is scaled down such that all values below 120 are collapsed to 0
-			// (apparent silence), as the level very rarely drops that low.
-			volume := math.Max(math.Min(float64(maxSample-120)/120, 1), 0)
-			// Smooth out sudden bar movement
-			volumeDisplayed := volume
-			if volumeDisplayed > prevVolumeDisplayed {
-				extraVolume := volumeDisplayed - prevVolumeDisplayed
-				if extraVolume > 0.02 {
-					volumeDisplayed = prevVolumeDisplayed + extraVolume/3
-				}
-			} else {
-				lostVolume := prevVolumeDisplayed - volumeDisplayed
-				if lostVolume > 0.02 {
-					volumeDisplayed = prevVolumeDisplayed - lostVolume/3
-				}
-			}
-			prevVolumeDisplayed = volumeDisplayed
-
-			// Update bar
-			app.QueueUpdateDraw(func() {
-				// Update background color to indicate whether the audio clipped recently
-				curBackgroundColor := monitorBarFiller.GetBackgroundColor()
-				if curBackgroundColor == backgroundColorClipping {
-					if !clippingTracker.ClippedRecently {
-						monitorBarFiller.SetBackgroundColor(tcell.ColorDefault)
-					}
-				} else if clippingTracker.ClippedRecently {
-					monitorBarFiller.SetBackgroundColor(backgroundColorClipping)
-				}
-
-				// Update bar length to indicate current volume
-				_, _, barWidth, barHeight := monitorBar.GetInnerRect()
-				barLength := barHeight
-				if flagHorizontal {
-					barLength = barWidth
-				}
-				monitorBar.ResizeItem(monitorBarFiller, int(math.Floor((1-volumeDisplayed)*float64(barLength))), 0)
-			})
-		},
+		log.Fatalf("%v", err)
+	}
+
+	deviceMatch := flagDevice
+	if deviceMatch == "" {
+		deviceMatch = cfg.Device
+	}
+	barColor := cfg.BarColor
+	if flagBarColor != "" {
+		barColor = flagBarColor
+	}
+	clippingColor := cfg.ClippingColor
+	if flagClippingColor != "" {
+		clippingColor = flagClippingColor
+	}
+	rangeDB := cfg.RangeDB
+	if flagRangeDB > 0 {
+		rangeDB = flagRangeDB
+	}
+	clipHoldDecay := cfg.ClipHoldDecay
+	if flagClipHoldDecay > 0 {
+		clipHoldDecay = flagClipHoldDecay
+	}
+	clipHoldSeconds := cfg.ClipHoldDuration
+	if flagClipHoldSeconds > 0 {
+		clipHoldSeconds = flagClipHoldSeconds
+	}
+	frameRate := cfg.FrameRate
+	if flagFrameRate > 0 {
+		frameRate = int(flagFrameRate)
+	}
+	meterType := cfg.MeterType
+	if flagMeterType != "" {
+		meterType = flagMeterType
+	}
+
+	keys := cfg.Keybindings
+	if flagKeyQuit != "" {
+		keys.Quit = flagKeyQuit
+	}
+	if flagKeyFreeze != "" {
+		keys.Freeze = flagKeyFreeze
+	}
+	if flagKeyOrientation != "" {
+		keys.ToggleOrientation = flagKeyOrientation
+	}
+	if flagKeyCycleDevice != "" {
+		keys.CycleDevice = flagKeyCycleDevice
+	}
+	if flagKeyResetPeak != "" {
+		keys.ResetPeak = flagKeyResetPeak
+	}
+
+	m, err := monitor.New(monitor.Config{
+		Horizontal:               flagHorizontal || cfg.Horizontal,
+		DeviceMatch:              deviceMatch,
+		Loopback:                 flagLoopback,
+		RangeDB:                  rangeDB,
+		Channels:                 uint32(flagChannels),
+		History:                  flagHistory,
+		Spectrogram:              flagSpectrogram,
+		ServeAddr:                flagServeAddr,
+		BarColor:                 tcell.GetColor(barColor),
+		ClippingColor:            tcell.GetColor(clippingColor),
+		ClipHoldDecayDBPerSecond: clipHoldDecay,
+		ClipHoldDurationSeconds:  clipHoldSeconds,
+		FrameRate:                frameRate,
+		MeterType:                meterType,
+		Keybindings:              keys,
 	})
 	if err != nil {
-		log.Fatalf("failed to initialize audio capture device: %v", err)
+		log.Fatalf("%v", err)
 	}
+	defer m.Close()
 
-	// Display the tui & monitor audio input
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		if err := app.SetRoot(monitorBar, true).SetFocus((monitorBar)).Run(); err != nil {
-			log.Printf("closed unsuccessfully: %v", err)
+	if flagListDevices {
+		devices, err := m.Devices()
+		if err != nil {
+			log.Fatalf("failed to enumerate capture devices: %v", err)
 		}
-		done <- syscall.SIGTERM
-	}()
-	if err = device.Start(); err != nil {
-		log.Fatalf("failed to start audio capture device: %v", err)
+		fmt.Print(monitor.FormatDeviceList(devices))
+		os.Exit(0)
 	}
 
-	// Block until a shutoff signal is recieved
-	<-done
-	device.Uninit()
-	app.Stop()
+	if err := m.Run(); err != nil {
+		log.Fatalf("%v", err)
+	}
 	os.Exit(0)
 }
 
@@ -171,28 +155,41 @@ func init() {
 	flagHorizontalInfo := flagInfo{false, "orient the monitor horizontally"}
 	flag.BoolVar(&flagHorizontal, "horizontal", flagHorizontalInfo.fallback, flagHorizontalInfo.usage)
 	flag.BoolVar(&flagHorizontal, "h", flagHorizontalInfo.fallback, flagHorizontalInfo.usageShorthand())
-}
 
-type recentClippingTracker struct {
-	ClippedRecently    bool
-	cancelPendingReset func()
-}
+	flagListDevicesInfo := flagInfo{false, "list available capture devices and exit"}
+	flag.BoolVar(&flagListDevices, "list-devices", flagListDevicesInfo.fallback, flagListDevicesInfo.usage)
+	flag.BoolVar(&flagListDevices, "L", flagListDevicesInfo.fallback, flagListDevicesInfo.usageShorthand())
 
-// IndicateClippingOccured is called to indicate that audio clipping has been detected.
-// `recentClippingTracker.ClippedRecently` remains true for 3 seconds after clipping occurs.
-func (c *recentClippingTracker) IndicateClippingOccured() {
-	if c.cancelPendingReset != nil {
-		c.cancelPendingReset()
-	}
-	c.ClippedRecently = true
-	cancelled := false
-	c.cancelPendingReset = func() {
-		cancelled = true
-	}
-	go func() {
-		time.Sleep(volumeClipWarningDuration)
-		if !cancelled {
-			c.ClippedRecently = false
-		}
-	}()
+	flag.StringVar(&flagDevice, "device", "", "substring match against capture device names")
+	flag.StringVar(&flagDevice, "d", "", "substring match against capture device names (shorthand)")
+
+	flagLoopbackInfo := flagInfo{false, "monitor system playback output instead of a capture device"}
+	flag.BoolVar(&flagLoopback, "loopback", flagLoopbackInfo.fallback, flagLoopbackInfo.usage)
+	flag.BoolVar(&flagLoopback, "l", flagLoopbackInfo.fallback, flagLoopbackInfo.usageShorthand())
+
+	flag.Float64Var(&flagRangeDB, "range", 0, "dBFS range covered by the meter scale (default 60)")
+
+	flag.UintVar(&flagChannels, "channels", 0, "number of channels to render as separate bars (default 2)")
+	flag.UintVar(&flagChannels, "c", 0, "number of channels to render as separate bars (default 2, shorthand)")
+
+	flagHistoryInfo := flagInfo{false, "show a scrolling waveform history instead of a single bar"}
+	flag.BoolVar(&flagHistory, "history", flagHistoryInfo.fallback, flagHistoryInfo.usage)
+	flag.BoolVar(&flagHistory, "H", flagHistoryInfo.fallback, flagHistoryInfo.usageShorthand())
+
+	flag.BoolVar(&flagSpectrogram, "spectrogram", false, "add a frequency-domain spectrogram below the waveform history (requires --history or --meter history)")
+
+	flag.StringVar(&flagServeAddr, "serve", "", "run headless, exposing Prometheus metrics and a WebSocket stream at this address instead of the tui (e.g. :9090)")
+
+	flag.StringVar(&flagBarColor, "bar-color", "", "meter bar fill color (default from config, or green)")
+	flag.StringVar(&flagClippingColor, "clipping-color", "", "meter background color after a clip (default from config, or red)")
+	flag.Float64Var(&flagClipHoldDecay, "clip-hold-decay", 0, "peak hold decay rate in dB/sec (default from config, or 20)")
+	flag.Float64Var(&flagClipHoldSeconds, "clip-hold-duration", 0, "seconds the clip background stays lit after a clip (default from config, or 3)")
+	flag.UintVar(&flagFrameRate, "frame-rate", 0, "capture periods delivered per second (default from config, or 60)")
+	flag.StringVar(&flagMeterType, "meter", "", `meter type, "bar" or "history" (default from config, or "bar")`)
+
+	flag.StringVar(&flagKeyQuit, "key-quit", "", "key that quits the tui (default from config, or q)")
+	flag.StringVar(&flagKeyFreeze, "key-freeze", "", "key that freezes/unfreezes the meter (default from config, or f)")
+	flag.StringVar(&flagKeyOrientation, "key-orientation", "", "key that toggles bar orientation (default from config, or o)")
+	flag.StringVar(&flagKeyCycleDevice, "key-cycle-device", "", "key that cycles to the next capture device (default from config, or d)")
+	flag.StringVar(&flagKeyResetPeak, "key-reset-peak", "", "key that resets the peak hold (default from config, or r)")
 }
@@ -0,0 +1,109 @@
+// Package config loads audmon's user-facing settings from a YAML file,
+// providing the defaults that apply when no file (or an incomplete one) is
+// found.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Keybindings maps meter actions to the single keys that trigger them.
+type Keybindings struct {
+	ToggleOrientation string `yaml:"toggle_orientation"`
+	Freeze            string `yaml:"freeze"`
+	CycleDevice       string `yaml:"cycle_device"`
+	ResetPeak         string `yaml:"reset_peak"`
+	Quit              string `yaml:"quit"`
+}
+
+// Config holds every user-tunable audmon setting that can come from the
+// YAML config file, the corresponding CLI flags, or both.
+type Config struct {
+	Horizontal bool   `yaml:"horizontal"`
+	BarColor   string `yaml:"bar_color"`
+	// ClippingColor fills the unlit background behind a channel's bar for
+	// ClipHoldDuration after it clips; the bar itself keeps BarColor.
+	ClippingColor string `yaml:"clipping_color"`
+	// ClipHoldDecay is how quickly the peak hold tick falls back toward the
+	// live signal once it stops being exceeded, in dB/sec. This is this
+	// meter's equivalent of the old linear meter's smoothing factor, now
+	// expressed as a dBFS decay rate rather than a fixed per-frame fraction.
+	ClipHoldDecay float64 `yaml:"clip_hold_decay_db_per_sec"`
+	// ClipHoldDuration is how long the clip background stays lit after the
+	// last period in which a channel clipped, in seconds.
+	ClipHoldDuration float64 `yaml:"clip_hold_duration_sec"`
+	// RangeDB is the span, in dB below 0 dBFS, covered by the meter scale.
+	RangeDB float64 `yaml:"range_db"`
+	// FrameRate is how many capture periods malgo delivers per second.
+	FrameRate int `yaml:"frame_rate"`
+	// MeterType is "bar" or "history".
+	MeterType string `yaml:"meter_type"`
+	// Device is a case-insensitive substring match against capture device
+	// names, used to select a device non-interactively.
+	Device      string      `yaml:"device"`
+	Keybindings Keybindings `yaml:"keybindings"`
+}
+
+// Defaults returns the built-in Config used when no config file is found,
+// and as the base a config file's settings are merged onto.
+func Defaults() Config {
+	return Config{
+		Horizontal:       false,
+		BarColor:         "green",
+		ClippingColor:    "red",
+		ClipHoldDecay:    20.0,
+		ClipHoldDuration: 3.0,
+		RangeDB:          60.0,
+		FrameRate:        60,
+		MeterType:        "bar",
+		Keybindings: Keybindings{
+			ToggleOrientation: "o",
+			Freeze:            "f",
+			CycleDevice:       "d",
+			ResetPeak:         "r",
+			Quit:              "q",
+		},
+	}
+}
+
+// Path returns the config file location audmon searches:
+// $XDG_CONFIG_HOME/audmon/config.yaml, falling back to
+// $HOME/.config/audmon/config.yaml when XDG_CONFIG_HOME is unset.
+func Path() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "audmon", "config.yaml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "audmon", "config.yaml"), nil
+}
+
+// Load reads the config file at Path, if one exists, and merges its
+// settings onto Defaults. A missing config file is not an error.
+func Load() (Config, error) {
+	cfg := Defaults()
+
+	path, err := Path()
+	if err != nil {
+		return cfg, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
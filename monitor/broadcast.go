@@ -0,0 +1,47 @@
+package monitor
+
+import "sync"
+
+// broadcaster fans a stream of Readings out to any number of subscribers,
+// so the tview renderer and a headless HTTP server can each drive
+// themselves off the same sample-processing pipeline without knowing about
+// each other. Slow subscribers drop frames rather than blocking capture.
+type broadcaster struct {
+	mu   sync.Mutex
+	subs map[chan Reading]struct{}
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{subs: make(map[chan Reading]struct{})}
+}
+
+// subscribe returns a new channel that will receive every Reading published
+// after this call, until unsubscribe is called with it.
+func (b *broadcaster) subscribe() chan Reading {
+	ch := make(chan Reading, 4)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+// unsubscribe stops delivery to ch and closes it.
+func (b *broadcaster) unsubscribe(ch chan Reading) {
+	b.mu.Lock()
+	delete(b.subs, ch)
+	b.mu.Unlock()
+	close(ch)
+}
+
+// publish delivers reading to every current subscriber, dropping it for any
+// subscriber whose buffer is full instead of blocking the audio callback.
+func (b *broadcaster) publish(reading Reading) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- reading:
+		default:
+		}
+	}
+}
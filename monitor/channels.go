@@ -0,0 +1,24 @@
+package monitor
+
+import "fmt"
+
+// defaultChannelLabels returns conventional channel names for common
+// speaker layouts, falling back to "Ch1", "Ch2", ... for anything else.
+func defaultChannelLabels(n int) []string {
+	switch n {
+	case 1:
+		return []string{"M"}
+	case 2:
+		return []string{"L", "R"}
+	case 6:
+		return []string{"L", "R", "C", "LFE", "Ls", "Rs"}
+	case 8:
+		return []string{"L", "R", "C", "LFE", "Ls", "Rs", "Lrs", "Rrs"}
+	}
+
+	labels := make([]string, n)
+	for i := range labels {
+		labels[i] = fmt.Sprintf("Ch%d", i+1)
+	}
+	return labels
+}
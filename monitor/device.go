@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gen2brain/malgo"
+	"github.com/rivo/tview"
+)
+
+// selectDeviceFrom resolves which capture device Run should open from an
+// already-enumerated device list: an explicit substring match from Config,
+// the sole device if only one exists, or an interactive tview picker when
+// several are available and none was named.
+func (m *Monitor) selectDeviceFrom(devices []malgo.DeviceInfo) (*malgo.DeviceInfo, error) {
+	if len(devices) == 0 {
+		if m.Loopback() {
+			return nil, fmt.Errorf("no loopback-capable devices found")
+		}
+		return nil, fmt.Errorf("no capture devices found")
+	}
+
+	if m.cfg.DeviceMatch != "" {
+		match := strings.ToLower(m.cfg.DeviceMatch)
+		for i := range devices {
+			if strings.Contains(strings.ToLower(devices[i].Name()), match) {
+				return &devices[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no capture device matching %q", m.cfg.DeviceMatch)
+	}
+
+	if len(devices) == 1 {
+		return &devices[0], nil
+	}
+
+	return m.pickDevice(devices)
+}
+
+// pickDevice prompts the user to choose among multiple capture devices with
+// a full-screen tview list, returning once a selection is made.
+func (m *Monitor) pickDevice(devices []malgo.DeviceInfo) (*malgo.DeviceInfo, error) {
+	picker := tview.NewApplication()
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).SetTitle(" select a capture device ")
+
+	var selected *malgo.DeviceInfo
+	for i := range devices {
+		device := &devices[i]
+		list.AddItem(device.Name(), "", 0, func() {
+			selected = device
+			picker.Stop()
+		})
+	}
+
+	if err := picker.SetRoot(list, true).SetFocus(list).Run(); err != nil {
+		return nil, fmt.Errorf("failed to run device picker: %w", err)
+	}
+	if selected == nil {
+		return nil, fmt.Errorf("no capture device selected")
+	}
+	return selected, nil
+}
+
+// filterMonitorSources narrows a capture device list down to PulseAudio
+// monitor sources, which by convention have IDs ending in ".monitor" (the
+// device's Name is a human-readable description like "Monitor of Built-in
+// Audio", not the ID, so the suffix has to be matched there instead). These
+// mirror the corresponding playback device's output, which is what a Linux
+// loopback capture wants.
+func filterMonitorSources(devices []malgo.DeviceInfo) []malgo.DeviceInfo {
+	var monitors []malgo.DeviceInfo
+	for _, device := range devices {
+		if strings.HasSuffix(device.ID.String(), ".monitor") {
+			monitors = append(monitors, device)
+		}
+	}
+	return monitors
+}
+
+// deviceIndexOf returns target's position in devices, or 0 if it isn't
+// found (which shouldn't happen, since target always comes from the same
+// enumeration as devices).
+func deviceIndexOf(devices []malgo.DeviceInfo, target *malgo.DeviceInfo) int {
+	for i := range devices {
+		if devices[i].ID == target.ID {
+			return i
+		}
+	}
+	return 0
+}
+
+// FormatDeviceList renders devices as "[index] name (id)" lines, one per
+// device, for use by --list-devices.
+func FormatDeviceList(devices []malgo.DeviceInfo) string {
+	var b strings.Builder
+	for i, device := range devices {
+		fmt.Fprintf(&b, "[%d] %s (%s)\n", i, device.Name(), device.ID.String())
+	}
+	return b.String()
+}
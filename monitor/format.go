@@ -0,0 +1,68 @@
+package monitor
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/gen2brain/malgo"
+)
+
+// bytesPerSample returns the frame width of a single sample in format.
+func bytesPerSample(format malgo.FormatType) int {
+	switch format {
+	case malgo.FormatS16:
+		return 2
+	case malgo.FormatS32, malgo.FormatF32:
+		return 4
+	default: // malgo.FormatU8
+		return 1
+	}
+}
+
+// normalizeSamples decodes pSample into float64 samples scaled to
+// [-1.0, 1.0], regardless of the device's native PCM format.
+func normalizeSamples(format malgo.FormatType, pSample []byte) []float64 {
+	width := bytesPerSample(format)
+	count := len(pSample) / width
+	samples := make([]float64, count)
+
+	switch format {
+	case malgo.FormatS16:
+		for i := 0; i < count; i++ {
+			v := int16(binary.LittleEndian.Uint16(pSample[i*width : i*width+width]))
+			samples[i] = float64(v) / 32768
+		}
+	case malgo.FormatS32:
+		for i := 0; i < count; i++ {
+			v := int32(binary.LittleEndian.Uint32(pSample[i*width : i*width+width]))
+			samples[i] = float64(v) / 2147483648
+		}
+	case malgo.FormatF32:
+		for i := 0; i < count; i++ {
+			bits := binary.LittleEndian.Uint32(pSample[i*width : i*width+width])
+			samples[i] = float64(math.Float32frombits(bits))
+		}
+	default: // malgo.FormatU8, unsigned with 128 as the silent midpoint
+		for i := 0; i < count; i++ {
+			samples[i] = (float64(pSample[i]) - 128) / 128
+		}
+	}
+
+	return samples
+}
+
+// clipThreshold is the normalized amplitude at or above which a sample of
+// format is considered clipped: exactly 1.0 for floating point formats, or
+// within 1 LSB of full scale for integer formats.
+func clipThreshold(format malgo.FormatType) float64 {
+	switch format {
+	case malgo.FormatS16:
+		return 1 - 1.0/32768
+	case malgo.FormatS32:
+		return 1 - 1.0/2147483648
+	case malgo.FormatF32:
+		return 1
+	default: // malgo.FormatU8
+		return 1 - 1.0/128
+	}
+}
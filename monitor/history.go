@@ -0,0 +1,213 @@
+package monitor
+
+import (
+	"math"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"gonum.org/v1/gonum/dsp/fourier"
+)
+
+// spectrogramFFTSize is the window length fed to the FFT for each column of
+// the spectrogram. It slides over the most recent samples rather than
+// aligning to period boundaries, trading exact block framing for a display
+// that updates every period.
+const spectrogramFFTSize = 1024
+
+// historyPoint is one period's peak/RMS pair, each taken from the loudest
+// channel.
+type historyPoint struct {
+	PeakDBFS float64
+	RMSDBFS  float64
+}
+
+// historyView renders a scrolling time-domain waveform of recent periods
+// and, optionally, a frequency-domain spectrogram beneath it. Both are
+// drawn directly via SetDrawFunc rather than composed from tview boxes,
+// since each cell's color depends on data the Flex-based bar layout has no
+// way to express.
+type historyView struct {
+	mu      sync.Mutex
+	rangeDB float64
+	points  []historyPoint
+
+	spectrogram bool
+	fft         *fourier.FFT
+	monoRing    []float64
+	columns     [][]float64
+
+	waveform *tview.Box
+	spectro  *tview.Box
+	root     *tview.Flex
+}
+
+func newHistoryView(rangeDB float64, spectrogram bool) *historyView {
+	h := &historyView{
+		rangeDB:     rangeDB,
+		spectrogram: spectrogram,
+	}
+	if spectrogram {
+		h.fft = fourier.NewFFT(spectrogramFFTSize)
+		h.monoRing = make([]float64, spectrogramFFTSize)
+	}
+
+	h.waveform = tview.NewBox().SetDrawFunc(h.drawWaveform)
+	h.root = tview.NewFlex().SetDirection(tview.FlexRow)
+	h.root.AddItem(h.waveform, 0, 1, false)
+
+	if spectrogram {
+		h.spectro = tview.NewBox().SetDrawFunc(h.drawSpectrogram)
+		h.root.AddItem(h.spectro, 0, 1, false)
+	}
+
+	return h
+}
+
+// Root is the primitive to install as the application's root.
+func (h *historyView) Root() tview.Primitive {
+	return h.root
+}
+
+// Push records one period's combined peak/RMS and, when the
+// spectrogram is enabled, feeds mono into the sliding FFT window and
+// records the resulting magnitude spectrum as a new column.
+func (h *historyView) Push(peakDBFS, rmsDBFS float64, mono []float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.points = append(h.points, historyPoint{PeakDBFS: peakDBFS, RMSDBFS: rmsDBFS})
+	const maxPoints = 4096
+	if len(h.points) > maxPoints {
+		h.points = h.points[len(h.points)-maxPoints:]
+	}
+
+	if !h.spectrogram {
+		return
+	}
+
+	for _, s := range mono {
+		h.monoRing = append(h.monoRing[1:], s)
+	}
+	windowed := make([]float64, spectrogramFFTSize)
+	for i, s := range h.monoRing {
+		// Hann window to reduce spectral leakage at the slice edges.
+		w := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(spectrogramFFTSize-1))
+		windowed[i] = s * w
+	}
+	coeffs := h.fft.Coefficients(nil, windowed)
+	magnitudes := make([]float64, len(coeffs))
+	for i, c := range coeffs {
+		magnitudes[i] = amplitudeToDBFS(cmplxAbs(c) / spectrogramFFTSize)
+	}
+
+	h.columns = append(h.columns, magnitudes)
+	const maxColumns = 4096
+	if len(h.columns) > maxColumns {
+		h.columns = h.columns[len(h.columns)-maxColumns:]
+	}
+}
+
+func (h *historyView) drawWaveform(screen tcell.Screen, x, y, width, height int) (int, int, int, int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	points := h.points
+	if len(points) > width {
+		points = points[len(points)-width:]
+	}
+	offset := width - len(points)
+
+	for i, p := range points {
+		col := x + offset + i
+		peakRow := y + height - 1 - clampInt(int(math.Round(dBFSPosition(p.PeakDBFS, h.rangeDB)*float64(height-1))), 0, height-1)
+		rmsRow := y + height - 1 - clampInt(int(math.Round(dBFSPosition(p.RMSDBFS, h.rangeDB)*float64(height-1))), 0, height-1)
+		for row := y + height - 1; row >= y; row-- {
+			style := tcell.StyleDefault
+			switch {
+			case row == peakRow:
+				style = style.Foreground(peakTickColor)
+				screen.SetContent(col, row, '─', nil, style)
+			case row >= rmsRow:
+				style = style.Foreground(barColor)
+				screen.SetContent(col, row, '█', nil, style)
+			}
+		}
+	}
+	return x, y, width, height
+}
+
+func (h *historyView) drawSpectrogram(screen tcell.Screen, x, y, width, height int) (int, int, int, int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	columns := h.columns
+	if len(columns) > width {
+		columns = columns[len(columns)-width:]
+	}
+	offset := width - len(columns)
+
+	for i, col := range columns {
+		for row := 0; row < height; row++ {
+			// Log-ish band spacing: low rows (near the bottom) cover a
+			// narrow slice of low frequency bins, high rows a wide slice
+			// of high frequency bins.
+			fracLow := float64(row) / float64(height)
+			fracHigh := float64(row+1) / float64(height)
+			binLow := int(math.Pow(fracLow, 2) * float64(len(col)-1))
+			binHigh := clampInt(int(math.Pow(fracHigh, 2)*float64(len(col)-1)), binLow+1, len(col))
+
+			mag := silenceFloorDBFS
+			for _, v := range col[binLow:binHigh] {
+				if v > mag {
+					mag = v
+				}
+			}
+			normalized := dBFSPosition(mag, h.rangeDB)
+
+			drawX, drawY := x+offset+i, y+height-1-row
+			screen.SetContent(drawX, drawY, '█', nil, tcell.StyleDefault.Foreground(viridis(normalized)))
+		}
+	}
+	return x, y, width, height
+}
+
+func cmplxAbs(c complex128) float64 {
+	return math.Hypot(real(c), imag(c))
+}
+
+// viridis approximates the viridis colormap by linearly interpolating
+// between a handful of anchor colors across t in [0, 1].
+func viridis(t float64) tcell.Color {
+	type stop struct {
+		t       float64
+		r, g, b int32
+	}
+	stops := []stop{
+		{0.00, 68, 1, 84},
+		{0.25, 59, 82, 139},
+		{0.50, 33, 145, 140},
+		{0.75, 94, 201, 98},
+		{1.00, 253, 231, 37},
+	}
+
+	if t <= stops[0].t {
+		return tcell.NewRGBColor(stops[0].r, stops[0].g, stops[0].b)
+	}
+	last := stops[len(stops)-1]
+	if t >= last.t {
+		return tcell.NewRGBColor(last.r, last.g, last.b)
+	}
+
+	for i := 1; i < len(stops); i++ {
+		if t > stops[i].t {
+			continue
+		}
+		prev := stops[i-1]
+		cur := stops[i]
+		frac := (t - prev.t) / (cur.t - prev.t)
+		lerp := func(a, b int32) int32 { return a + int32(frac*float64(b-a)) }
+		return tcell.NewRGBColor(lerp(prev.r, cur.r), lerp(prev.g, cur.g), lerp(prev.b, cur.b))
+	}
+	return tcell.NewRGBColor(last.r, last.g, last.b)
+}
@@ -0,0 +1,70 @@
+package monitor
+
+import "math"
+
+// biquad is a direct-form-II-transposed second order IIR section.
+type biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+	z1, z2     float64
+}
+
+func (bq *biquad) process(x float64) float64 {
+	y := bq.b0*x + bq.z1
+	bq.z1 = bq.b1*x - bq.a1*y + bq.z2
+	bq.z2 = bq.b2*x - bq.a2*y
+	return y
+}
+
+// kWeightingFilter applies the ITU-R BS.1770 K-weighting curve: a high-shelf
+// pre-filter that approximates the acoustic effect of a human head, followed
+// by an RLB high-pass that rolls off subsonic content. Coefficients are
+// derived per sample rate via the bilinear transform, following the
+// reference filter design used by BS.1770 implementations.
+type kWeightingFilter struct {
+	preFilter biquad
+	rlbFilter biquad
+}
+
+func newKWeightingFilter(sampleRate uint32) *kWeightingFilter {
+	rate := float64(sampleRate)
+
+	// Stage 1: high-shelf pre-filter.
+	const (
+		preF0 = 1681.974450955533
+		preG  = 3.999843853973347
+		preQ  = 0.7071752369554196
+	)
+	k := math.Tan(math.Pi * preF0 / rate)
+	vh := math.Pow(10, preG/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/preQ + k*k
+	pre := biquad{
+		b0: (vh + vb*k/preQ + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/preQ + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/preQ + k*k) / a0,
+	}
+
+	// Stage 2: RLB (revised low-frequency B-curve) high-pass.
+	const (
+		rlbF0 = 38.13547087602444
+		rlbQ  = 0.5003270373238773
+	)
+	k = math.Tan(math.Pi * rlbF0 / rate)
+	a0 = 1 + k/rlbQ + k*k
+	rlb := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/rlbQ + k*k) / a0,
+	}
+
+	return &kWeightingFilter{preFilter: pre, rlbFilter: rlb}
+}
+
+func (f *kWeightingFilter) process(x float64) float64 {
+	return f.rlbFilter.process(f.preFilter.process(x))
+}
@@ -0,0 +1,252 @@
+package monitor
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gen2brain/malgo"
+)
+
+// silenceFloorDBFS is the displayed floor for readings with effectively no
+// signal, so -Inf never reaches the renderer.
+const silenceFloorDBFS = -120.0
+
+// peakHoldDecayDBPerSecond is the default rate at which the peak hold tick
+// falls back toward the live signal once it stops being exceeded, used when
+// Config.ClipHoldDecayDBPerSecond is unset.
+const peakHoldDecayDBPerSecond = 20.0
+
+// lufsWindow is the BS.1770 momentary loudness integration window.
+const lufsWindow = 400 * time.Millisecond
+
+// ChannelReading is one channel's worth of a period's meter output.
+type ChannelReading struct {
+	PeakDBFS     float64
+	RMSDBFS      float64
+	PeakHoldDBFS float64
+	Clipped      bool
+}
+
+// Reading is a single period's worth of meter output across all channels.
+type Reading struct {
+	Channels []ChannelReading
+	// LUFS is a single momentary loudness figure summed across channels, per
+	// BS.1770 (there is no meaningful per-channel LUFS).
+	LUFS float64
+	// Correlation is sum(L*R)/sqrt(sum(L^2)*sum(R^2)) over the period.
+	// Only meaningful when there are exactly two channels.
+	Correlation float64
+}
+
+// Meter turns raw interleaved capture samples into per-channel dBFS
+// peak/RMS levels with a decaying peak hold, plus a single K-weighted
+// momentary LUFS reading and (for stereo) a phase correlation reading, per
+// ITU-R BS.1770.
+type Meter struct {
+	format   malgo.FormatType
+	channels int
+
+	kWeights []*kWeightingFilter
+
+	// mu guards every field below, since Process runs on the audio capture
+	// callback's goroutine while ResetPeakHold is called from wherever the
+	// UI dispatches input handling (a different goroutine).
+	mu sync.Mutex
+
+	holdDecayDBPerSecond float64
+	peakHoldDBFS         []float64
+	lastHoldUpdate       time.Time
+
+	lufsBlocks    []float64
+	lufsTimestamp []time.Time
+}
+
+// NewMeter constructs a Meter for the given capture format, sample rate, and
+// channel count. holdDecayDBPerSecond is how quickly the peak hold tick
+// falls back toward the live signal; zero or negative means
+// peakHoldDecayDBPerSecond.
+func NewMeter(format malgo.FormatType, sampleRate, channels uint32, holdDecayDBPerSecond float64) *Meter {
+	n := int(channels)
+	if n < 1 {
+		n = 1
+	}
+	if holdDecayDBPerSecond <= 0 {
+		holdDecayDBPerSecond = peakHoldDecayDBPerSecond
+	}
+
+	kWeights := make([]*kWeightingFilter, n)
+	peakHold := make([]float64, n)
+	for ch := range kWeights {
+		kWeights[ch] = newKWeightingFilter(sampleRate)
+		peakHold[ch] = silenceFloorDBFS
+	}
+
+	return &Meter{
+		format:               format,
+		channels:             n,
+		kWeights:             kWeights,
+		holdDecayDBPerSecond: holdDecayDBPerSecond,
+		peakHoldDBFS:         peakHold,
+		lastHoldUpdate:       time.Now(),
+	}
+}
+
+// ResetPeakHold clears every channel's peak hold back to the silence floor,
+// e.g. in response to a user-triggered reset.
+func (m *Meter) ResetPeakHold() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for ch := range m.peakHoldDBFS {
+		m.peakHoldDBFS[ch] = silenceFloorDBFS
+	}
+}
+
+// Process de-interleaves one period of samples by channel and returns the
+// resulting meter Reading.
+func (m *Meter) Process(pSample []byte) Reading {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	samples := normalizeSamples(m.format, pSample)
+	threshold := clipThreshold(m.format)
+	now := time.Now()
+	elapsedHoldDecay := m.holdDecayDBPerSecond * now.Sub(m.lastHoldUpdate).Seconds()
+	m.lastHoldUpdate = now
+
+	peak := make([]float64, m.channels)
+	sumSquares := make([]float64, m.channels)
+	kSumSquares := make([]float64, m.channels)
+	clipped := make([]bool, m.channels)
+	frames := 0
+
+	for i, s := range samples {
+		ch := i % m.channels
+		if ch == 0 {
+			frames++
+		}
+
+		a := math.Abs(s)
+		if a > peak[ch] {
+			peak[ch] = a
+		}
+		if a >= threshold {
+			clipped[ch] = true
+		}
+		sumSquares[ch] += s * s
+		weighted := m.kWeights[ch].process(s)
+		kSumSquares[ch] += weighted * weighted
+	}
+
+	readings := make([]ChannelReading, m.channels)
+	var kSumSquaresTotal float64
+	for ch := 0; ch < m.channels; ch++ {
+		rms := 0.0
+		if frames > 0 {
+			rms = math.Sqrt(sumSquares[ch] / float64(frames))
+		}
+		peakDBFS := amplitudeToDBFS(peak[ch])
+
+		m.peakHoldDBFS[ch] -= elapsedHoldDecay
+		if peakDBFS > m.peakHoldDBFS[ch] {
+			m.peakHoldDBFS[ch] = peakDBFS
+		}
+		if m.peakHoldDBFS[ch] < silenceFloorDBFS {
+			m.peakHoldDBFS[ch] = silenceFloorDBFS
+		}
+
+		readings[ch] = ChannelReading{
+			PeakDBFS:     peakDBFS,
+			RMSDBFS:      amplitudeToDBFS(rms),
+			PeakHoldDBFS: m.peakHoldDBFS[ch],
+			Clipped:      clipped[ch],
+		}
+		kSumSquaresTotal += kSumSquares[ch]
+	}
+
+	blockMeanSquare := 0.0
+	if frames > 0 {
+		blockMeanSquare = kSumSquaresTotal / float64(frames)
+	}
+	m.pushLUFSBlock(blockMeanSquare, now)
+
+	return Reading{
+		Channels:    readings,
+		LUFS:        m.momentaryLUFS(),
+		Correlation: correlation(samples, m.channels),
+	}
+}
+
+// correlation computes sum(L*R) / sqrt(sum(L^2) * sum(R^2)) for a stereo
+// interleaved buffer; for any other channel count there is no well-defined
+// stereo correlation, so it reports 0.
+func correlation(samples []float64, channels int) float64 {
+	if channels != 2 {
+		return 0
+	}
+
+	var sumLR, sumLL, sumRR float64
+	for i := 0; i+1 < len(samples); i += 2 {
+		l, r := samples[i], samples[i+1]
+		sumLR += l * r
+		sumLL += l * l
+		sumRR += r * r
+	}
+
+	denom := math.Sqrt(sumLL * sumRR)
+	if denom == 0 {
+		return 0
+	}
+	return sumLR / denom
+}
+
+// pushLUFSBlock records a block's mean-square power and discards any blocks
+// that have aged out of the 400ms momentary window.
+func (m *Meter) pushLUFSBlock(meanSquare float64, now time.Time) {
+	m.lufsBlocks = append(m.lufsBlocks, meanSquare)
+	m.lufsTimestamp = append(m.lufsTimestamp, now)
+
+	cutoff := now.Add(-lufsWindow)
+	drop := 0
+	for drop < len(m.lufsTimestamp) && m.lufsTimestamp[drop].Before(cutoff) {
+		drop++
+	}
+	m.lufsBlocks = m.lufsBlocks[drop:]
+	m.lufsTimestamp = m.lufsTimestamp[drop:]
+}
+
+// momentaryLUFS averages the mean-square power over the momentary window
+// and applies the BS.1770 K-weighted loudness offset.
+func (m *Meter) momentaryLUFS() float64 {
+	if len(m.lufsBlocks) == 0 {
+		return silenceFloorDBFS
+	}
+	var sum float64
+	for _, v := range m.lufsBlocks {
+		sum += v
+	}
+	meanPower := sum / float64(len(m.lufsBlocks))
+	if meanPower <= 0 {
+		return silenceFloorDBFS
+	}
+	lufs := -0.691 + 10*math.Log10(meanPower)
+	if lufs < silenceFloorDBFS {
+		return silenceFloorDBFS
+	}
+	return lufs
+}
+
+// amplitudeToDBFS converts a linear [0, 1] amplitude to dBFS, clamped at
+// silenceFloorDBFS so silence renders as the bottom of the scale rather
+// than negative infinity.
+func amplitudeToDBFS(amplitude float64) float64 {
+	if amplitude <= 0 {
+		return silenceFloorDBFS
+	}
+	db := 20 * math.Log10(amplitude)
+	if db < silenceFloorDBFS {
+		return silenceFloorDBFS
+	}
+	return db
+}
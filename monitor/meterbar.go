@@ -0,0 +1,243 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const peakTickColor = tcell.ColorYellow
+
+// meterChannel is one channel's level indicator: a dBFS bar with a decaying
+// peak hold tick, labeled underneath ("L", "R", "C", ...).
+//
+// The bar is built from a stack of boxes inside a tview.Flex: filler boxes
+// (resized every frame to consume unfilled space) and a single dynamic,
+// colored box that fills whatever space the fillers leave behind. This
+// mirrors the original linear VU meter's technique of growing a box by
+// shrinking the filler ahead of it, extended with a second filler either
+// side of a 1-cell peak tick.
+//
+// For a horizontal orientation the fill should grow from the left rather
+// than the bottom, so the whole segment order is reversed relative to the
+// vertical layout; see SetHorizontal for how that plays out against tview.Flex,
+// which always lays items out start-to-end along its direction axis.
+type meterChannel struct {
+	horizontal       bool
+	clipHoldDuration time.Duration
+	clipUntil        time.Time
+
+	level     *tview.Flex
+	topFiller *tview.Box
+	peakTick  *tview.Box
+	midFiller *tview.Box
+	bar       *tview.Box
+
+	label     *tview.TextView
+	container *tview.Flex
+}
+
+func newMeterChannel(horizontal bool, label string, clipHoldDuration time.Duration) *meterChannel {
+	c := &meterChannel{
+		clipHoldDuration: clipHoldDuration,
+		level:            tview.NewFlex(),
+		topFiller:        tview.NewBox(),
+		peakTick:         tview.NewBox().SetBackgroundColor(peakTickColor),
+		midFiller:        tview.NewBox(),
+		bar:              tview.NewBox().SetBackgroundColor(barColor),
+		label:            tview.NewTextView().SetText(label).SetTextAlign(tview.AlignCenter),
+	}
+	c.SetHorizontal(horizontal)
+
+	c.container = tview.NewFlex().SetDirection(tview.FlexRow)
+	c.container.AddItem(c.level, 0, 1, false)
+	c.container.AddItem(c.label, 1, 0, false)
+
+	return c
+}
+
+// SetHorizontal re-orients the bar, rebuilding the segment order described
+// in the meterChannel doc comment for the new direction.
+func (c *meterChannel) SetHorizontal(horizontal bool) {
+	c.horizontal = horizontal
+
+	direction := tview.FlexRow
+	if horizontal {
+		direction = tview.FlexColumn
+	}
+	c.level.SetDirection(direction)
+
+	order := []*tview.Box{c.topFiller, c.peakTick, c.midFiller, c.bar}
+	if horizontal {
+		for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+			order[i], order[j] = order[j], order[i]
+		}
+	}
+	c.level.Clear()
+	for _, box := range order {
+		proportion := 0
+		if box == c.bar {
+			proportion = 1
+		}
+		c.level.AddItem(box, 0, proportion, false)
+	}
+}
+
+// update positions the bar and peak tick to reflect reading against a dBFS
+// scale spanning [-rangeDB, 0].
+func (c *meterChannel) update(reading ChannelReading, rangeDB float64) {
+	now := time.Now()
+	if reading.Clipped {
+		c.clipUntil = now.Add(c.clipHoldDuration)
+	}
+	color := tcell.ColorDefault
+	if now.Before(c.clipUntil) {
+		color = backgroundColorClipping
+	}
+	c.topFiller.SetBackgroundColor(color)
+	c.midFiller.SetBackgroundColor(color)
+
+	_, _, width, height := c.level.GetInnerRect()
+	total := height
+	if c.horizontal {
+		total = width
+	}
+
+	barLen := clampInt(int(math.Round(dBFSPosition(reading.RMSDBFS, rangeDB)*float64(total))), 0, total)
+	unfilled := total - barLen
+
+	peakOffset := clampInt(int(math.Round((1-dBFSPosition(reading.PeakHoldDBFS, rangeDB))*float64(total))), 0, unfilled)
+	tickLen := 0
+	if unfilled > 0 {
+		tickLen = 1
+	}
+	if peakOffset+tickLen > unfilled {
+		peakOffset = unfilled - tickLen
+	}
+	midLen := unfilled - peakOffset - tickLen
+
+	c.level.ResizeItem(c.topFiller, peakOffset, 0)
+	c.level.ResizeItem(c.peakTick, tickLen, 0)
+	c.level.ResizeItem(c.midFiller, midLen, 0)
+}
+
+// meterBar renders a Reading across one or more channel bars side by side,
+// with a phase correlation gauge (stereo only) and a peak/RMS/LUFS/clip
+// text row.
+type meterBar struct {
+	channels    []*meterChannel
+	correlation *tview.TextView
+	readoutRow  *tview.TextView
+	root        *tview.Flex
+}
+
+func newMeterBar(horizontal bool, labels []string, clipHoldDuration time.Duration) *meterBar {
+	b := &meterBar{
+		readoutRow: tview.NewTextView().SetDynamicColors(true),
+	}
+
+	channelsRow := tview.NewFlex().SetDirection(tview.FlexColumn)
+	for _, label := range labels {
+		ch := newMeterChannel(horizontal, label, clipHoldDuration)
+		b.channels = append(b.channels, ch)
+		channelsRow.AddItem(ch.container, 0, 1, false)
+	}
+
+	b.root = tview.NewFlex().SetDirection(tview.FlexRow)
+	b.root.AddItem(channelsRow, 0, 1, true)
+	if len(labels) == 2 {
+		b.correlation = tview.NewTextView().SetDynamicColors(true)
+		b.root.AddItem(b.correlation, 1, 0, false)
+	}
+	b.root.AddItem(b.readoutRow, 1, 0, false)
+
+	return b
+}
+
+// Root is the primitive to install as the application's root.
+func (b *meterBar) Root() tview.Primitive {
+	return b.root
+}
+
+// SetHorizontal re-orients every channel bar in place, for the
+// toggle-orientation keybinding.
+func (b *meterBar) SetHorizontal(horizontal bool) {
+	for _, ch := range b.channels {
+		ch.SetHorizontal(horizontal)
+	}
+}
+
+// Update positions every channel bar, the correlation gauge, and the
+// readout row to reflect reading against a dBFS scale spanning
+// [-rangeDB, 0].
+func (b *meterBar) Update(reading Reading, rangeDB float64) {
+	clipped := false
+	for i, channelReading := range reading.Channels {
+		if i >= len(b.channels) {
+			break
+		}
+		b.channels[i].update(channelReading, rangeDB)
+		clipped = clipped || channelReading.Clipped
+	}
+
+	if b.correlation != nil {
+		b.correlation.Clear()
+		fmt.Fprintf(b.correlation, "corr %+.2f  %s", reading.Correlation, correlationGauge(reading.Correlation, 21))
+	}
+
+	clipText := ""
+	if clipped {
+		clipText = " [red]CLIP[-]"
+	}
+	b.readoutRow.Clear()
+	peak, rms := aggregateChannels(reading.Channels)
+	fmt.Fprintf(b.readoutRow, "peak %6.1f dBFS  rms %6.1f dBFS  LUFS %6.1f%s",
+		peak, rms, reading.LUFS, clipText)
+}
+
+// correlationGauge renders a width-cell bracketed gauge with a marker at the
+// position corresponding to corr, which ranges over [-1, 1].
+func correlationGauge(corr float64, width int) string {
+	pos := clampInt(int(math.Round((corr+1)/2*float64(width-1))), 0, width-1)
+	var b strings.Builder
+	b.WriteByte('[')
+	for i := 0; i < width; i++ {
+		if i == pos {
+			b.WriteByte('|')
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// dBFSPosition maps a dBFS reading to a [0, 1] fraction of a meter spanning
+// [-rangeDB, 0] dBFS, clamping values outside that range.
+func dBFSPosition(dbfs, rangeDB float64) float64 {
+	if rangeDB <= 0 {
+		return 0
+	}
+	pos := (dbfs + rangeDB) / rangeDB
+	if pos < 0 {
+		return 0
+	}
+	if pos > 1 {
+		return 1
+	}
+	return pos
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,477 @@
+// Package monitor implements the core audmon capture loop: selecting a
+// device, reading samples from malgo, and driving the tview meter.
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/MichaelDarr/audmon/config"
+	"github.com/gdamore/tcell/v2"
+	"github.com/gen2brain/malgo"
+	"github.com/rivo/tview"
+)
+
+const (
+	// defaultBarColor and defaultClippingColor are used when
+	// Config.BarColor/ClippingColor are unset (tcell.ColorDefault).
+	defaultBarColor      = tcell.ColorGreen
+	defaultClippingColor = tcell.ColorRed
+	// defaultFrameRate is used when Config.FrameRate is unset.
+	defaultFrameRate = 60
+	// defaultSampleRate is requested explicitly so the K-weighting filter's
+	// coefficients are derived for a known rate rather than whatever the
+	// device happens to negotiate.
+	defaultSampleRate = 48000
+	// defaultRangeDB is how far below 0 dBFS the meter scale extends when
+	// Config.RangeDB is unset.
+	defaultRangeDB = 60.0
+	// defaultClipHoldDurationSeconds is used when
+	// Config.ClipHoldDurationSeconds is unset.
+	defaultClipHoldDurationSeconds = 3.0
+	// defaultChannels is used when Config.Channels is unset; the device's
+	// own channel count is not queried.
+	defaultChannels = 2
+	// defaultMeterType is used when Config.MeterType is unset.
+	defaultMeterType = "bar"
+)
+
+// barColor and backgroundColorClipping are resolved once per Run, from
+// Config (see Monitor.barColor/clippingColor), and read by meterbar.go and
+// history.go while rendering.
+var (
+	barColor                = defaultBarColor
+	backgroundColorClipping = defaultClippingColor
+)
+
+// Config controls how a Monitor selects a device and renders the meter.
+type Config struct {
+	// Horizontal orients the monitor bar left-to-right instead of bottom-to-top.
+	Horizontal bool
+	// DeviceMatch, when non-empty, is matched as a case-insensitive substring
+	// against capture device names to select a device non-interactively.
+	DeviceMatch string
+	// Loopback, when true, monitors system playback output instead of a
+	// capture device: WASAPI loopback on Windows, or a PulseAudio monitor
+	// source on Linux.
+	Loopback bool
+	// RangeDB is the span, in dB below 0 dBFS, covered by the meter scale.
+	// Zero means defaultRangeDB.
+	RangeDB float64
+	// Channels is the number of interleaved channels to request from the
+	// device and render as separate bars. Zero means defaultChannels.
+	Channels uint32
+	// History, when true, replaces the bar meter with a scrolling
+	// time-domain waveform of recent periods.
+	History bool
+	// Spectrogram, when true alongside the history view (History, or
+	// MeterType "history"), adds a frequency-domain spectrogram beneath the
+	// waveform.
+	Spectrogram bool
+	// ServeAddr, when non-empty, runs audmon headless: instead of a tview
+	// meter, it starts a Server listening on this address and publishes
+	// every Reading to it.
+	ServeAddr string
+	// BarColor is the fill color for the meter bar / waveform.
+	// tcell.ColorDefault means defaultBarColor.
+	BarColor tcell.Color
+	// ClippingColor fills the unlit background behind a channel's bar while
+	// its clip hold is active; the bar itself keeps BarColor.
+	// tcell.ColorDefault means defaultClippingColor.
+	ClippingColor tcell.Color
+	// ClipHoldDecayDBPerSecond is how quickly the peak hold tick falls back
+	// toward the live signal once it stops being exceeded. Zero or negative
+	// means peakHoldDecayDBPerSecond.
+	ClipHoldDecayDBPerSecond float64
+	// ClipHoldDurationSeconds is how long the clip background stays lit
+	// after the last period in which a channel clipped. Zero or negative
+	// means defaultClipHoldDurationSeconds.
+	ClipHoldDurationSeconds float64
+	// FrameRate is how many capture periods malgo delivers per second. Zero
+	// or negative means defaultFrameRate.
+	FrameRate int
+	// MeterType selects "bar" or "history" as an alternative to the History
+	// bool, for config-file-driven selection. Empty means defaultMeterType.
+	// History, if set, always takes precedence.
+	MeterType string
+	// Keybindings customizes which keys toggle orientation, freeze/unfreeze
+	// the meter, cycle capture devices, reset the peak hold, and quit.
+	Keybindings config.Keybindings
+}
+
+// channels returns the configured channel count, falling back to the
+// default used when Config.Channels is unset.
+func (m *Monitor) channels() uint32 {
+	if m.cfg.Channels > 0 {
+		return m.cfg.Channels
+	}
+	return defaultChannels
+}
+
+// barColor returns the configured bar color, falling back to the default.
+func (m *Monitor) barColor() tcell.Color {
+	if m.cfg.BarColor != tcell.ColorDefault {
+		return m.cfg.BarColor
+	}
+	return defaultBarColor
+}
+
+// clippingColor returns the configured clipping color, falling back to the
+// default.
+func (m *Monitor) clippingColor() tcell.Color {
+	if m.cfg.ClippingColor != tcell.ColorDefault {
+		return m.cfg.ClippingColor
+	}
+	return defaultClippingColor
+}
+
+// clipHoldDecay returns the configured peak hold decay rate, falling back
+// to the default.
+func (m *Monitor) clipHoldDecay() float64 {
+	if m.cfg.ClipHoldDecayDBPerSecond > 0 {
+		return m.cfg.ClipHoldDecayDBPerSecond
+	}
+	return peakHoldDecayDBPerSecond
+}
+
+// clipHoldDuration returns the configured clip background hold duration,
+// falling back to the default.
+func (m *Monitor) clipHoldDuration() time.Duration {
+	seconds := defaultClipHoldDurationSeconds
+	if m.cfg.ClipHoldDurationSeconds > 0 {
+		seconds = m.cfg.ClipHoldDurationSeconds
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// frameRate returns the configured capture frame rate, falling back to the
+// default.
+func (m *Monitor) frameRate() int {
+	if m.cfg.FrameRate > 0 {
+		return m.cfg.FrameRate
+	}
+	return defaultFrameRate
+}
+
+// historyMode reports whether Run should show the scrolling waveform
+// history view instead of the bar meter, per Config.History or
+// Config.MeterType.
+func (m *Monitor) historyMode() bool {
+	if m.cfg.History {
+		return true
+	}
+	meterType := m.cfg.MeterType
+	if meterType == "" {
+		meterType = defaultMeterType
+	}
+	return meterType == "history"
+}
+
+// rangeDB returns the configured meter range, falling back to the default.
+func (m *Monitor) rangeDB() float64 {
+	if m.cfg.RangeDB > 0 {
+		return m.cfg.RangeDB
+	}
+	return defaultRangeDB
+}
+
+// loopbackDeviceType is the malgo device type to enumerate when Loopback is
+// set. WASAPI exposes loopback capture against playback devices; PulseAudio
+// exposes its monitor sources as ordinary capture devices, so elsewhere we
+// enumerate Capture and filter for ".monitor" IDs instead.
+func (m *Monitor) loopbackDeviceType() malgo.DeviceType {
+	if runtime.GOOS == "windows" {
+		return malgo.Playback
+	}
+	return malgo.Capture
+}
+
+// Monitor owns the malgo context and audio device used to drive the meter.
+type Monitor struct {
+	cfg    Config
+	ctx    *malgo.AllocatedContext
+	app    *tview.Application
+	device *malgo.Device
+}
+
+// New initializes a malgo context and returns a Monitor ready to Run.
+func New(cfg Config) (*Monitor, error) {
+	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, func(message string) {
+		log.Printf("malgo: %v", message)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize malgo context: %w", err)
+	}
+
+	return &Monitor{
+		cfg: cfg,
+		ctx: ctx,
+		app: tview.NewApplication(),
+	}, nil
+}
+
+// Close releases the malgo context and any device owned by the Monitor.
+func (m *Monitor) Close() {
+	if m.device != nil {
+		m.device.Uninit()
+	}
+	_ = m.ctx.Uninit()
+	m.ctx.Free()
+}
+
+// Devices lists the devices relevant to the current Config: capture devices
+// normally, or the devices a loopback capture would be selected from.
+func (m *Monitor) Devices() ([]malgo.DeviceInfo, error) {
+	if !m.Loopback() {
+		return m.ctx.Devices(malgo.Capture)
+	}
+
+	devices, err := m.ctx.Devices(m.loopbackDeviceType())
+	if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS == "windows" {
+		return devices, nil
+	}
+	return filterMonitorSources(devices), nil
+}
+
+// Loopback reports whether the Monitor is configured to capture system
+// playback output rather than a microphone.
+func (m *Monitor) Loopback() bool {
+	return m.cfg.Loopback
+}
+
+// Run selects a capture device (per Config, or interactively), wires up
+// either the tview meter or, if Config.ServeAddr is set, a headless Server,
+// and blocks until the user quits or the process is signaled.
+func (m *Monitor) Run() error {
+	devices, err := m.Devices()
+	if err != nil {
+		return fmt.Errorf("failed to enumerate capture devices: %w", err)
+	}
+	deviceInfo, err := m.selectDeviceFrom(devices)
+	if err != nil {
+		return err
+	}
+	deviceIndex := deviceIndexOf(devices, deviceInfo)
+
+	rangeDB := m.rangeDB()
+	channels := m.channels()
+	headless := m.cfg.ServeAddr != ""
+	barColor, backgroundColorClipping = m.barColor(), m.clippingColor()
+
+	var bar *meterBar
+	var history *historyView
+	var root tview.Primitive
+	var server *Server
+	bcast := newBroadcaster()
+	switch {
+	case headless:
+		server = NewServer(m.cfg.ServeAddr)
+	case m.historyMode():
+		history = newHistoryView(rangeDB, m.cfg.Spectrogram)
+		root = history.Root()
+	default:
+		bar = newMeterBar(m.cfg.Horizontal, defaultChannelLabels(int(channels)), m.clipHoldDuration())
+		root = bar.Root()
+	}
+
+	// Configure audio capture device. On Windows, loopback monitors a
+	// playback device directly via WASAPI loopback; everywhere else
+	// "loopback" just means we picked a PulseAudio monitor source, which
+	// behaves like an ordinary capture device.
+	deviceType := malgo.Capture
+	if m.cfg.Loopback && runtime.GOOS == "windows" {
+		deviceType = malgo.Loopback
+	}
+	captureDeviceConfig := malgo.DefaultDeviceConfig(deviceType)
+	captureDeviceConfig.Capture.Format = malgo.FormatU8
+	if deviceType == malgo.Loopback {
+		// WASAPI loopback commonly delivers 16-bit or float samples rather
+		// than the U8 audmon otherwise requests.
+		captureDeviceConfig.Capture.Format = malgo.FormatS16
+	}
+	captureDeviceConfig.Capture.Channels = channels
+	captureDeviceConfig.SampleRate = defaultSampleRate
+	captureDeviceConfig.Alsa.NoMMap = 1
+	captureDeviceConfig.PeriodSizeInMilliseconds = uint32((time.Second / time.Duration(m.frameRate())).Milliseconds())
+
+	meter := NewMeter(captureDeviceConfig.Capture.Format, captureDeviceConfig.SampleRate, captureDeviceConfig.Capture.Channels, m.clipHoldDecay())
+
+	var frozen atomic.Bool
+	openDevice := func(info *malgo.DeviceInfo) (malgo.Device, error) {
+		captureDeviceConfig.Capture.DeviceID = info.ID.Pointer()
+		return malgo.InitDevice(m.ctx.Context, captureDeviceConfig, malgo.DeviceCallbacks{
+			Data: func(_, pSample []byte, _ uint32) {
+				if frozen.Load() {
+					return
+				}
+				reading := meter.Process(pSample)
+				if history != nil {
+					mono := downmix(normalizeSamples(captureDeviceConfig.Capture.Format, pSample), int(captureDeviceConfig.Capture.Channels))
+					peak, rms := aggregateChannels(reading.Channels)
+					history.Push(peak, rms, mono)
+				}
+				bcast.publish(reading)
+			},
+		})
+	}
+
+	device, err := openDevice(deviceInfo)
+	if err != nil {
+		return fmt.Errorf("failed to initialize audio capture device: %w", err)
+	}
+	m.device = &device
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+	sub := bcast.subscribe()
+	defer bcast.unsubscribe(sub)
+
+	if headless {
+		go func() {
+			for reading := range sub {
+				server.Publish(reading)
+			}
+		}()
+		go func() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("server closed unsuccessfully: %v", err)
+			}
+			done <- syscall.SIGTERM
+		}()
+	} else {
+		go func() {
+			for reading := range sub {
+				reading := reading
+				if history != nil {
+					m.app.QueueUpdateDraw(func() {})
+					continue
+				}
+				m.app.QueueUpdateDraw(func() {
+					bar.Update(reading, rangeDB)
+				})
+			}
+		}()
+
+		keys := m.cfg.Keybindings
+		m.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			key := keyRune(event)
+			if key == "" {
+				return event
+			}
+			switch key {
+			case keys.Quit:
+				done <- syscall.SIGTERM
+				return nil
+			case keys.Freeze:
+				frozen.Store(!frozen.Load())
+				return nil
+			case keys.ResetPeak:
+				meter.ResetPeakHold()
+				return nil
+			case keys.ToggleOrientation:
+				if bar != nil {
+					m.cfg.Horizontal = !m.cfg.Horizontal
+					bar.SetHorizontal(m.cfg.Horizontal)
+				}
+				return nil
+			case keys.CycleDevice:
+				if len(devices) > 1 {
+					deviceIndex = (deviceIndex + 1) % len(devices)
+					next := &devices[deviceIndex]
+					newDevice, err := openDevice(next)
+					if err != nil {
+						log.Printf("failed to switch to device %q: %v", next.Name(), err)
+						return nil
+					}
+					if err := newDevice.Start(); err != nil {
+						log.Printf("failed to start device %q: %v", next.Name(), err)
+						return nil
+					}
+					device.Uninit()
+					device = newDevice
+				}
+				return nil
+			}
+			return event
+		})
+
+		go func() {
+			if err := m.app.SetRoot(root, true).SetFocus(root).Run(); err != nil {
+				log.Printf("closed unsuccessfully: %v", err)
+			}
+			done <- syscall.SIGTERM
+		}()
+	}
+
+	if err = device.Start(); err != nil {
+		return fmt.Errorf("failed to start audio capture device: %w", err)
+	}
+
+	// Block until a shutoff signal is recieved
+	<-done
+	device.Uninit()
+	if headless {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	} else {
+		m.app.Stop()
+	}
+	return nil
+}
+
+// keyRune returns the single character a key event represents, or "" for
+// anything that isn't a plain rune keypress (arrows, function keys, ...),
+// for comparison against Config.Keybindings.
+func keyRune(event *tcell.EventKey) string {
+	if event.Key() != tcell.KeyRune {
+		return ""
+	}
+	return string(event.Rune())
+}
+
+// downmix averages interleaved samples across channels into a single mono
+// stream, for the waveform/spectrogram history view.
+func downmix(samples []float64, channels int) []float64 {
+	if channels < 1 {
+		channels = 1
+	}
+	frames := len(samples) / channels
+	mono := make([]float64, frames)
+	for i := range mono {
+		var sum float64
+		for ch := 0; ch < channels; ch++ {
+			sum += samples[i*channels+ch]
+		}
+		mono[i] = sum / float64(channels)
+	}
+	return mono
+}
+
+// aggregateChannels collapses per-channel readings into a single peak/RMS
+// pair by taking the loudest channel of each, for displays (like the
+// history view) that show one combined trace rather than one per channel.
+func aggregateChannels(channels []ChannelReading) (peakDBFS, rmsDBFS float64) {
+	peakDBFS, rmsDBFS = silenceFloorDBFS, silenceFloorDBFS
+	for _, ch := range channels {
+		if ch.PeakDBFS > peakDBFS {
+			peakDBFS = ch.PeakDBFS
+		}
+		if ch.RMSDBFS > rmsDBFS {
+			rmsDBFS = ch.RMSDBFS
+		}
+	}
+	return peakDBFS, rmsDBFS
+}
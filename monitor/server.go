@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Server exposes a Reading stream over HTTP for headless monitoring: a
+// Prometheus /metrics endpoint, and a /ws endpoint that streams one JSON
+// frame per Reading to any connected browser dashboard.
+type Server struct {
+	httpServer *http.Server
+	upgrader   websocket.Upgrader
+
+	peakGauge   prometheus.Gauge
+	rmsGauge    prometheus.Gauge
+	lufsGauge   prometheus.Gauge
+	clipCounter prometheus.Counter
+
+	bcast *broadcaster
+}
+
+// NewServer builds a Server that will listen on addr once ListenAndServe is
+// called.
+func NewServer(addr string) *Server {
+	registry := prometheus.NewRegistry()
+	s := &Server{
+		bcast: newBroadcaster(),
+		peakGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "audmon_peak_dbfs",
+			Help: "Most recent instantaneous peak level, in dBFS, across all channels.",
+		}),
+		rmsGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "audmon_rms_dbfs",
+			Help: "Most recent RMS level, in dBFS, across all channels.",
+		}),
+		lufsGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "audmon_lufs",
+			Help: "Most recent BS.1770 K-weighted momentary loudness, in LUFS.",
+		}),
+		clipCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "audmon_clip_total",
+			Help: "Number of periods in which any channel clipped.",
+		}),
+	}
+	registry.MustRegister(s.peakGauge, s.rmsGauge, s.lufsGauge, s.clipCounter)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/ws", s.handleWebSocket)
+	s.httpServer = &http.Server{Addr: addr, Handler: mux}
+
+	return s
+}
+
+// Publish records reading against the Prometheus gauges and fans it out to
+// any connected WebSocket clients.
+func (s *Server) Publish(reading Reading) {
+	peak, rms := aggregateChannels(reading.Channels)
+	s.peakGauge.Set(peak)
+	s.rmsGauge.Set(rms)
+	s.lufsGauge.Set(reading.LUFS)
+	if anyClipped(reading.Channels) {
+		s.clipCounter.Inc()
+	}
+	s.bcast.publish(reading)
+}
+
+// ListenAndServe blocks serving /metrics and /ws until the server is shut
+// down, at which point it returns http.ErrServerClosed.
+func (s *Server) ListenAndServe() error {
+	return s.httpServer.ListenAndServe()
+}
+
+// Shutdown gracefully stops the HTTP server.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ch := s.bcast.subscribe()
+	defer s.bcast.unsubscribe(ch)
+
+	for reading := range ch {
+		if err := conn.WriteJSON(reading); err != nil {
+			return
+		}
+	}
+}
+
+// anyClipped reports whether any channel clipped in a Reading.
+func anyClipped(channels []ChannelReading) bool {
+	for _, ch := range channels {
+		if ch.Clipped {
+			return true
+		}
+	}
+	return false
+}